@@ -449,6 +449,33 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:  "ipconflict",
+		Usage: "IP address conflicts",
+		Subcommands: []cli.Command{
+			{
+				Name:      "ls",
+				Aliases:   []string{"list"},
+				Usage:     "List active IP address conflicts",
+				ArgsUsage: " ",
+				Flags:     []cli.Flag{jsonFlag},
+				Action:    listIPConflicts,
+			},
+		},
+	},
+	{
+		Name:  "objdb",
+		Usage: "state store client of netmaster",
+		Subcommands: []cli.Command{
+			{
+				Name:      "inspect",
+				Usage:     "Inspect the state store client of netmaster",
+				ArgsUsage: " ",
+				Flags:     []cli.Flag{jsonFlag},
+				Action:    inspectObjdb,
+			},
+		},
+	},
 	{
 		Name:  "bgp",
 		Usage: "router capability configuration",
@@ -26,6 +26,14 @@ func versionURL(ctx *cli.Context) string {
 	return fmt.Sprintf("%s/version", baseURL(ctx))
 }
 
+func ipConflictsURL(ctx *cli.Context) string {
+	return fmt.Sprintf("%s/ipconflicts", baseURL(ctx))
+}
+
+func objdbInspectURL(ctx *cli.Context) string {
+	return fmt.Sprintf("%s/debug/objdb", baseURL(ctx))
+}
+
 func writeBody(resp *http.Response, ctx *cli.Context) {
 	content, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/codegangsta/cli"
 	contivClient "github.com/contiv/contivmodel/client"
@@ -904,6 +905,122 @@ func setGlobal(ctx *cli.Context) {
 	errCheck(ctx, getClient(ctx).GlobalPost(global))
 }
 
+// ipConflict is an IP conflict as reported by netmaster
+type ipConflict struct {
+	IPAddress string `json:"ipAddress"`
+	Type      string `json:"type"`
+	Endpoints []struct {
+		EndpointID    string `json:"endpointID"`
+		NetID         string `json:"netID"`
+		Tenant        string `json:"tenant"`
+		HomingHost    string `json:"homingHost"`
+		ContainerName string `json:"containerName"`
+	} `json:"endpoints"`
+	Since time.Time `json:"since"`
+}
+
+func listIPConflicts(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		errExit(ctx, exitHelp, "More arguments than required", true)
+	}
+
+	conflicts := []ipConflict{}
+	errCheck(ctx, getObject(ctx, ipConflictsURL(ctx), &conflicts))
+
+	if ctx.Bool("json") {
+		dumpJSONList(ctx, conflicts)
+	} else {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+		defer writer.Flush()
+		writer.Write([]byte("IP Address\tType\tTenant\tNetwork\tEndpoint\tHost\tContainer\tSince\n"))
+		writer.Write([]byte("----------\t----\t------\t-------\t--------\t----\t---------\t-----\n"))
+		for _, conflict := range conflicts {
+			for _, ep := range conflict.Endpoints {
+				writer.Write(
+					[]byte(fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+						conflict.IPAddress,
+						conflict.Type,
+						ep.Tenant,
+						strings.Split(ep.NetID, ".")[0],
+						ep.EndpointID,
+						ep.HomingHost,
+						ep.ContainerName,
+						conflict.Since.Format(time.RFC3339),
+					)))
+			}
+		}
+	}
+}
+
+// objdbInfo is the state store client of netmaster as inspected
+type objdbInfo struct {
+	Plugin        string
+	Endpoints     []string
+	KeyRoot       string
+	TLS           bool
+	Wrappers      []string
+	Registrations []struct {
+		ServiceName string
+		HostAddr    string
+		Port        int
+	}
+	Watches     int
+	WatchBudget *struct {
+		MaxStreams int
+		Streams    int
+		Shared     int
+	}
+	Cache *struct {
+		Keys   int
+		Hits   uint64
+		Misses uint64
+	}
+	RecentErrors []struct {
+		Time    time.Time
+		Backend string
+		Op      string
+		Error   string
+	}
+}
+
+func inspectObjdb(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		errExit(ctx, exitHelp, "More arguments than required", true)
+	}
+
+	info := objdbInfo{}
+	errCheck(ctx, getObject(ctx, objdbInspectURL(ctx), &info))
+
+	if ctx.Bool("json") {
+		dumpJSONList(ctx, info)
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	defer writer.Flush()
+	writer.Write([]byte(fmt.Sprintf("Plugin:\t%v\n", info.Plugin)))
+	writer.Write([]byte(fmt.Sprintf("Endpoints:\t%v\n", strings.Join(info.Endpoints, ","))))
+	writer.Write([]byte(fmt.Sprintf("Key root:\t%v\n", info.KeyRoot)))
+	writer.Write([]byte(fmt.Sprintf("TLS:\t%v\n", info.TLS)))
+	writer.Write([]byte(fmt.Sprintf("Wrappers:\t%v\n", strings.Join(info.Wrappers, ","))))
+	writer.Write([]byte(fmt.Sprintf("Watches:\t%v\n", info.Watches)))
+	if info.WatchBudget != nil {
+		writer.Write([]byte(fmt.Sprintf("Watch streams:\t%v of %v, %v watches shared\n",
+			info.WatchBudget.Streams, info.WatchBudget.MaxStreams, info.WatchBudget.Shared)))
+	}
+	if info.Cache != nil {
+		writer.Write([]byte(fmt.Sprintf("Cache:\t%v keys, %v hits, %v misses\n",
+			info.Cache.Keys, info.Cache.Hits, info.Cache.Misses)))
+	}
+	for _, reg := range info.Registrations {
+		writer.Write([]byte(fmt.Sprintf("Registration:\t%v %v:%v\n", reg.ServiceName, reg.HostAddr, reg.Port)))
+	}
+	for _, opErr := range info.RecentErrors {
+		writer.Write([]byte(fmt.Sprintf("Error:\t%v %v %v: %v\n",
+			opErr.Time.Format(time.RFC3339), opErr.Backend, opErr.Op, opErr.Error)))
+	}
+}
+
 func dumpJSONList(ctx *cli.Context, list interface{}) {
 	content, err := json.MarshalIndent(list, "", "  ")
 	if err != nil {
@@ -33,11 +33,13 @@ import (
 	"github.com/contiv/objdb"
 	"github.com/contiv/ofnet"
 	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
 
 	log "github.com/Sirupsen/logrus"
 )
 
-const leaderLockTTL = 30
+// Election netmaster replicas compete in for the leader role
+const leaderElection = "netmaster"
 
 // MasterDaemon runs the daemon FSM
 type MasterDaemon struct {
@@ -46,6 +48,8 @@ type MasterDaemon struct {
 	ClusterStore string // state store URL
 	ClusterMode  string // cluster scheduler used docker/kubernetes/mesos etc
 	DNSEnabled   bool   // Contiv skydns enabled?
+	AuditLog     string // File model changes are audited to, if any
+	AuditInStore bool   // Audit model changes in the state store
 
 	// Private state
 	currState        string                          // Current state of the daemon
@@ -54,12 +58,13 @@ type MasterDaemon struct {
 	resmgr           *resources.StateResourceManager // state resource manager
 	objdbClient      objdb.API                       // Objdb client
 	ofnetMaster      *ofnet.OfnetMaster              // Ofnet master instance
+	ipConflicts      *master.IPConflictDetector      // IP conflict detector
 	listenerMutex    sync.Mutex                      // Mutex for HTTP listener
 	stopLeaderChan   chan bool                       // Channel to stop the leader listener
 	stopFollowerChan chan bool                       // Channel to stop the follower listener
 }
 
-var leaderLock objdb.LockInterface // leader lock
+var leaderTask *objdb.LeaderTask // leader election
 
 // Init initializes the master daemon
 func (d *MasterDaemon) Init() {
@@ -87,11 +92,12 @@ func (d *MasterDaemon) Init() {
 		log.Fatalf("Failed to init resource manager. Error: %s", err)
 	}
 
-	// Create an objdb client
-	d.objdbClient, err = objdb.NewClient(d.ClusterStore)
+	// Create an objdb client, retrying transient store failures
+	objdbClient, err := objdb.NewClient(d.ClusterStore)
 	if err != nil {
 		log.Fatalf("Error connecting to state store: %v. Err: %v", d.ClusterStore, err)
 	}
+	d.objdbClient = objdb.NewRetryClient(objdbClient, objdb.DefaultRetryPolicy)
 }
 
 func (d *MasterDaemon) registerService() {
@@ -110,8 +116,8 @@ func (d *MasterDaemon) registerService() {
 		Role:        d.currState,
 	}
 
-	// Register the node with service registry
-	err = d.objdbClient.RegisterService(srvInfo)
+	// Register the node with service registry, again if it is lost
+	err = objdb.KeepRegistered(d.objdbClient, srvInfo)
 	if err != nil {
 		log.Fatalf("Error registering service. Err: %v", err)
 	}
@@ -125,8 +131,8 @@ func (d *MasterDaemon) registerService() {
 		Role:        d.currState,
 	}
 
-	// Register the node with service registry
-	err = d.objdbClient.RegisterService(srvInfo)
+	// Register the node with service registry, again if it is lost
+	err = objdb.KeepRegistered(d.objdbClient, srvInfo)
 	if err != nil {
 		log.Fatalf("Error registering service. Err: %v", err)
 	}
@@ -141,15 +147,53 @@ func (d *MasterDaemon) agentDiscoveryLoop() {
 	agentEventCh := make(chan objdb.WatchServiceEvent, 1)
 	watchStopCh := make(chan bool, 1)
 
-	// Start a watch on netplugin service
-	err := d.objdbClient.WatchService("netplugin", agentEventCh, watchStopCh)
+	// Start a watch on netplugin service. Events are queued while we are
+	// adding nodes, and repeated adds of an agent that re-registers are
+	// coalesced
+	_, err := objdb.WatchServiceBuffered(d.objdbClient, "netplugin", agentEventCh, watchStopCh,
+		objdb.WatchBufferConfig{CoalesceAdd: true})
 	if err != nil {
 		log.Fatalf("Could not start a watch on netplugin service. Err: %v", err)
 	}
 
+	// nodes we added, so nodes that went away during a resync are found
+	knownNodes := make(map[string]ofnet.OfnetNode)
+
 	for {
 		agentEv := <-agentEventCh
 		log.Debugf("Received netplugin watch event: %+v", agentEv)
+
+		// watch fell behind and dropped events, add all current nodes and
+		// remove the ones that are gone
+		if agentEv.EventType == objdb.WatchServiceEventResync {
+			srvList, err := d.objdbClient.GetService("netplugin")
+			if err != nil {
+				log.Errorf("Error getting netplugin nodes. Err: %v", err)
+				continue
+			}
+
+			currNodes := make(map[string]ofnet.OfnetNode)
+			for _, srvInfo := range srvList {
+				nodeInfo := ofnet.OfnetNode{
+					HostAddr: srvInfo.HostAddr,
+					HostPort: uint16(srvInfo.Port),
+				}
+				if err := d.ofnetMaster.AddNode(nodeInfo); err != nil {
+					log.Errorf("Error adding node %v. Err: %v", nodeInfo, err)
+				}
+				currNodes[nodeKey(nodeInfo)] = nodeInfo
+			}
+			for key, nodeInfo := range knownNodes {
+				if _, ok := currNodes[key]; !ok {
+					var res bool
+					log.Infof("Unregister node %+v", nodeInfo)
+					d.ofnetMaster.UnRegisterNode(&nodeInfo, &res)
+				}
+			}
+			knownNodes = currNodes
+			continue
+		}
+
 		// build host info
 		nodeInfo := ofnet.OfnetNode{
 			HostAddr: agentEv.ServiceInfo.HostAddr,
@@ -161,10 +205,12 @@ func (d *MasterDaemon) agentDiscoveryLoop() {
 			if err != nil {
 				log.Errorf("Error adding node %v. Err: %v", nodeInfo, err)
 			}
+			knownNodes[nodeKey(nodeInfo)] = nodeInfo
 		} else if agentEv.EventType == objdb.WatchServiceEventDel {
 			var res bool
 			log.Infof("Unregister node %+v", nodeInfo)
 			d.ofnetMaster.UnRegisterNode(&nodeInfo, &res)
+			delete(knownNodes, nodeKey(nodeInfo))
 		}
 
 		// Dont process next peer event for another 100ms
@@ -172,6 +218,11 @@ func (d *MasterDaemon) agentDiscoveryLoop() {
 	}
 }
 
+// nodeKey identifies an ofnet node by its address and port
+func nodeKey(nodeInfo ofnet.OfnetNode) string {
+	return fmt.Sprintf("%s:%d", nodeInfo.HostAddr, nodeInfo.HostPort)
+}
+
 // registerRoutes registers HTTP route handlers
 func (d *MasterDaemon) registerRoutes(router *mux.Router) {
 	// Add REST routes
@@ -214,6 +265,18 @@ func (d *MasterDaemon) registerRoutes(router *mux.Router) {
 	s.HandleFunc(fmt.Sprintf("/%s", master.GetServicesRESTEndpoint),
 		get(true, d.services))
 
+	// active IP conflicts
+	s.HandleFunc(fmt.Sprintf("/%s", master.GetIPConflictsRESTEndpoint), func(w http.ResponseWriter, r *http.Request) {
+		resp, err := json.Marshal(d.ipConflicts.Conflicts())
+		if err != nil {
+			http.Error(w,
+				core.Errorf("marshalling json failed. Error: %s", err).Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		w.Write(resp)
+	})
+
 	// Debug REST endpoint for inspecting ofnet state
 	s.HandleFunc("/debug/ofnet", func(w http.ResponseWriter, r *http.Request) {
 		ofnetMasterState, err := d.ofnetMaster.InspectState()
@@ -225,6 +288,18 @@ func (d *MasterDaemon) registerRoutes(router *mux.Router) {
 		w.Write(ofnetMasterState)
 	})
 
+	// Debug REST endpoint for inspecting the state store client
+	s.HandleFunc("/debug/objdb", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := json.Marshal(objdb.Inspect(d.objdbClient))
+		if err != nil {
+			http.Error(w,
+				core.Errorf("marshalling json failed. Error: %s", err).Error(),
+				http.StatusInternalServerError)
+			return
+		}
+		w.Write(resp)
+	})
+
 }
 
 // runLeader runs leader loop
@@ -235,12 +310,26 @@ func (d *MasterDaemon) runLeader() {
 	d.listenerMutex.Lock()
 	defer d.listenerMutex.Unlock()
 
+	// audit model changes if configured
+	var auditConfig *objdb.AuditConfig
+	if d.AuditLog != "" || d.AuditInStore {
+		auditConfig = &objdb.AuditConfig{FilePath: d.AuditLog, InStore: d.AuditInStore}
+	}
+
 	// Create a new api controller
-	d.apiController = objApi.NewAPIController(router, d.objdbClient, d.ClusterStore)
+	d.apiController = objApi.NewAPIController(router, d.objdbClient, d.ClusterStore, auditConfig)
 
 	//Restore state from clusterStore
 	d.restoreCache()
 
+	// watch endpoints for IP conflicts while we are the leader
+	if d.ipConflicts == nil {
+		d.ipConflicts = master.NewIPConflictDetector(d.stateDriver)
+	}
+	if err := d.ipConflicts.Start(); err != nil {
+		log.Errorf("Error starting IP conflict detector. Err: %v", err)
+	}
+
 	// Register netmaster service
 	d.registerService()
 
@@ -270,6 +359,7 @@ func (d *MasterDaemon) runLeader() {
 
 	// Close the listener and exit
 	listener.Close()
+	d.ipConflicts.Stop()
 	log.Infof("Exiting Leader mode")
 }
 
@@ -351,17 +441,15 @@ func (d *MasterDaemon) RunMasterFsm() {
 	// Register all existing netplugins in the background
 	go d.agentDiscoveryLoop()
 
-	// Create the lock
-	leaderLock, err = d.objdbClient.NewLock("netmaster/leader", localIP, leaderLockTTL)
-	if err != nil {
-		log.Fatalf("Could not create leader lock. Err: %v", err)
-	}
-
-	// Try to acquire the lock
-	err = leaderLock.Acquire(0)
+	// Compete for the leader role, leading till it is lost
+	leaderChan := make(chan bool, 1)
+	leaderTask, err = objdb.RunWhenLeaderAs(d.objdbClient, leaderElection, localIP, func(ctx context.Context) {
+		leaderChan <- true
+		<-ctx.Done()
+		leaderChan <- false
+	})
 	if err != nil {
-		// We dont expect any error during acquire.
-		log.Fatalf("Error while acquiring lock. Err: %v", err)
+		log.Fatalf("Could not compete for leader role. Err: %v", err)
 	}
 
 	// Initialize the stop channel
@@ -374,20 +462,16 @@ func (d *MasterDaemon) RunMasterFsm() {
 	// Start off being a follower
 	go d.runFollower()
 
-	// Main run loop waiting on leader lock
-	for {
-		// Wait for lock events
-		select {
-		case event := <-leaderLock.EventChan():
-			if event.EventType == objdb.LockAcquired {
-				log.Infof("Leader lock acquired")
+	// Main run loop waiting on leadership changes
+	for isLeader := range leaderChan {
+		if isLeader {
+			log.Infof("Elected leader, fencing token %d", leaderTask.FencingToken())
 
-				d.becomeLeader()
-			} else if event.EventType == objdb.LockLost {
-				log.Infof("Leader lock lost. Becoming follower")
+			d.becomeLeader()
+		} else {
+			log.Infof("Leadership lost. Becoming follower")
 
-				d.becomeFollower()
-			}
+			d.becomeFollower()
 		}
 	}
 }
@@ -409,8 +493,12 @@ func (d *MasterDaemon) getMasterInfo() (map[string]interface{}, error) {
 		return nil, errors.New("Error getting local IP address")
 	}
 
-	// get current holder of master lock
-	leader := leaderLock.GetHolder()
+	// get current leader
+	leader, err := leaderTask.Leader()
+	if err != nil {
+		log.Errorf("Error getting leader. Err: %v", err)
+		return nil, err
+	}
 	if leader == "" {
 		return nil, errors.New("Leader not found")
 	}
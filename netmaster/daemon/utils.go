@@ -62,6 +62,8 @@ func initStateDriver(clusterStore string) (core.StateDriver, error) {
 	// Make sure we support the statestore type
 	switch stateStore {
 	case utils.EtcdNameStr:
+	case utils.EtcdSRVNameStr:
+	case utils.EtcdTLSNameStr:
 	case utils.ConsulNameStr:
 	default:
 		return nil, core.Errorf("Unsupported state-store %q", stateStore)
@@ -96,9 +98,9 @@ func slaveProxyHandler(w http.ResponseWriter, r *http.Request) {
 		log.Fatalf("Error getting local IP address. Err: %v", err)
 	}
 
-	// get current holder of master lock
-	masterNode := leaderLock.GetHolder()
-	if masterNode == "" {
+	// get current leader
+	masterNode, err := leaderTask.Leader()
+	if err != nil || masterNode == "" {
 		http.Error(w, "Leader not found", http.StatusInternalServerError)
 		return
 	}
@@ -24,15 +24,19 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/contiv/netplugin/netmaster/daemon"
 	"github.com/contiv/netplugin/version"
+	"github.com/contiv/objdb"
 )
 
 type cliOpts struct {
 	help         bool
 	debug        bool
 	clusterStore string
+	clusterRoot  string
 	listenURL    string
 	clusterMode  string
 	dnsEnabled   bool
+	auditLog     string
+	auditInStore bool
 	version      bool
 }
 
@@ -57,6 +61,10 @@ func parseOpts(opts *cliOpts) error {
 		"cluster-store",
 		"etcd://127.0.0.1:2379",
 		"Etcd or Consul cluster store url.")
+	flagSet.StringVar(&opts.clusterRoot,
+		"cluster-root",
+		objdb.KeyRoot(),
+		"Root of the keys of this cluster in the cluster store, eg. cluster1/contiv.io")
 	flagSet.StringVar(&opts.listenURL,
 		"listen-url",
 		":9999",
@@ -69,6 +77,14 @@ func parseOpts(opts *cliOpts) error {
 		"dns-enable",
 		true,
 		"Turn on DNS {true, false}")
+	flagSet.StringVar(&opts.auditLog,
+		"audit-log",
+		"",
+		"Audit model changes to this file")
+	flagSet.BoolVar(&opts.auditInStore,
+		"audit-store",
+		false,
+		"Audit model changes in the cluster store")
 	flagSet.BoolVar(&opts.version,
 		"version",
 		false,
@@ -99,6 +115,11 @@ func execOpts(opts *cliOpts) {
 	if opts.debug {
 		log.SetLevel(log.DebugLevel)
 	}
+
+	// clusters sharing a store keep their keys apart
+	if err := objdb.SetKeyRoot(opts.clusterRoot); err != nil {
+		log.Fatalf("Invalid cluster-root %s. Err: %v", opts.clusterRoot, err)
+	}
 }
 
 func main() {
@@ -117,6 +138,8 @@ func main() {
 		ClusterStore: opts.clusterStore,
 		ClusterMode:  opts.clusterMode,
 		DNSEnabled:   opts.dnsEnabled,
+		AuditLog:     opts.auditLog,
+		AuditInStore: opts.auditInStore,
 	}
 
 	// initialize master daemon
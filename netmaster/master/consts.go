@@ -24,4 +24,6 @@ const (
 	GetServiceRESTEndpoint = "service"
 	//GetServicesRESTEndpoint is the REST endpoint to request info of all services
 	GetServicesRESTEndpoint = "services"
+	//GetIPConflictsRESTEndpoint is the REST endpoint to request active IP conflicts
+	GetIPConflictsRESTEndpoint = "ipconflicts"
 )
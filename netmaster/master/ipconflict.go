@@ -0,0 +1,409 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/contiv/netplugin/core"
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// IP conflict types
+const (
+	IPConflictEndpoint = "endpoint" // IP is used by two endpoints of a tenant
+	IPConflictTenant   = "tenant"   // IP is used in two tenants
+)
+
+// How long the endpoint watch may take to start. The endpoints are read
+// again after it, for updates made before the watch ran
+const ipConflictWatchDelay = 10 * time.Second
+
+// How often the endpoints are read again, for updates the watch missed
+const ipConflictResyncInterval = 5 * time.Minute
+
+// IP conflict event types
+const (
+	IPConflictRaised  = iota // Conflict was found or its endpoints changed
+	IPConflictCleared        // Conflict went away
+)
+
+// IPConflictEp is an endpoint involved in an IP conflict
+type IPConflictEp struct {
+	EndpointID    string `json:"endpointID"`
+	NetID         string `json:"netID"`
+	Tenant        string `json:"tenant"`
+	HomingHost    string `json:"homingHost"`
+	ContainerName string `json:"containerName"`
+}
+
+// IPConflict is an IP address used by more than one endpoint
+type IPConflict struct {
+	IPAddress string         `json:"ipAddress"`
+	Type      string         `json:"type"`
+	Endpoints []IPConflictEp `json:"endpoints"`
+	Since     time.Time      `json:"since"`
+}
+
+// IPConflictEvent is sent when a conflict is raised or cleared
+type IPConflictEvent struct {
+	EventType int
+	Conflict  IPConflict
+}
+
+// IPConflictDetector watches endpoint state for IP addresses used by more
+// than one endpoint
+type IPConflictDetector struct {
+	stateDriver core.StateDriver
+	endpoints   map[string]map[string]IPConflictEp     // ip -> endpoint id -> endpoint
+	epStates    map[string]*mastercfg.CfgEndpointState // endpoint id -> known state
+	epSeqs      map[string]uint64                      // endpoint id -> seq of its last watch event
+	seq         uint64                                 // watch events handled
+	conflicts   map[string]IPConflict                  // ip -> active conflict
+	listeners   []chan IPConflictEvent
+	watchOnce   sync.Once
+	stopCh      chan bool // closed by Stop, nil while stopped
+	mutex       sync.Mutex
+}
+
+// NewIPConflictDetector creates an IP conflict detector
+func NewIPConflictDetector(stateDriver core.StateDriver) *IPConflictDetector {
+	cd := &IPConflictDetector{stateDriver: stateDriver}
+	cd.reset()
+	return cd
+}
+
+// Start reads existing endpoints and watches for endpoint changes till
+// Stop is called. The watch may start after the read, so endpoints are
+// read again once it runs, and periodically after that
+func (cd *IPConflictDetector) Start() error {
+	cd.mutex.Lock()
+	if cd.stopCh != nil {
+		cd.mutex.Unlock()
+		return nil
+	}
+	stopCh := make(chan bool)
+	cd.stopCh = stopCh
+	cd.mutex.Unlock()
+
+	// the state driver watch can not be stopped, so it is started once and
+	// its events are ignored while the detector is stopped
+	cd.watchOnce.Do(func() {
+		epCfg := &mastercfg.CfgEndpointState{}
+		epCfg.StateDriver = cd.stateDriver
+
+		rsps := make(chan core.WatchState)
+		go cd.handleEvents(rsps)
+		go func() {
+			if err := epCfg.WatchAll(rsps); err != nil {
+				log.Errorf("Error watching endpoints for IP conflicts. Err: %v", err)
+			}
+		}()
+	})
+
+	if err := cd.resync(); err != nil {
+		cd.Stop()
+		return err
+	}
+
+	go cd.resyncLoop(stopCh)
+
+	return nil
+}
+
+// Stop stops detecting conflicts and forgets the endpoints, eg. when
+// netmaster loses leadership
+func (cd *IPConflictDetector) Stop() {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	if cd.stopCh == nil {
+		return
+	}
+	close(cd.stopCh)
+	cd.stopCh = nil
+	cd.reset()
+}
+
+// reset forgets all endpoints and conflicts. Caller must hold the mutex
+func (cd *IPConflictDetector) reset() {
+	cd.endpoints = make(map[string]map[string]IPConflictEp)
+	cd.epStates = make(map[string]*mastercfg.CfgEndpointState)
+	cd.epSeqs = make(map[string]uint64)
+	cd.conflicts = make(map[string]IPConflict)
+}
+
+// resyncLoop reads the endpoints again once the watch runs, and then at
+// the resync interval till stopped
+func (cd *IPConflictDetector) resyncLoop(stopCh chan bool) {
+	interval := ipConflictWatchDelay
+	for {
+		select {
+		case <-time.After(interval):
+			cd.resync()
+			interval = ipConflictResyncInterval
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// resync reads all endpoints and applies their differences from the known
+// endpoints. Endpoints with watch events during the read are left alone,
+// their events are more recent than the read
+func (cd *IPConflictDetector) resync() error {
+	cd.mutex.Lock()
+	startSeq := cd.seq
+	cd.mutex.Unlock()
+
+	epCfg := &mastercfg.CfgEndpointState{}
+	epCfg.StateDriver = cd.stateDriver
+	epCfgs, err := epCfg.ReadAll()
+	if err != nil && !strings.Contains(err.Error(), "Key not found") {
+		log.Errorf("Error reading endpoints. Err: %v", err)
+		return err
+	}
+
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	if cd.stopCh == nil {
+		return nil
+	}
+
+	current := make(map[string]*mastercfg.CfgEndpointState)
+	for _, state := range epCfgs {
+		epState := state.(*mastercfg.CfgEndpointState)
+		current[epState.ID] = epState
+	}
+	for epID, epState := range current {
+		if cd.epSeqs[epID] <= startSeq {
+			cd.setEndpoint(cd.epStates[epID], epState)
+		}
+	}
+	for epID, epState := range cd.epStates {
+		if current[epID] == nil && cd.epSeqs[epID] <= startSeq {
+			cd.setEndpoint(epState, nil)
+		}
+	}
+
+	// later reads start after these events
+	for epID, seq := range cd.epSeqs {
+		if seq <= startSeq {
+			delete(cd.epSeqs, epID)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe sends conflict events to eventCh. Events are dropped if the
+// channel is full
+func (cd *IPConflictDetector) Subscribe(eventCh chan IPConflictEvent) {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	cd.listeners = append(cd.listeners, eventCh)
+}
+
+// Conflicts returns the active conflicts, sorted by IP address
+func (cd *IPConflictDetector) Conflicts() []IPConflict {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	conflicts := []IPConflict{}
+	for _, conflict := range cd.conflicts {
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Sort(conflictsByIP(conflicts))
+
+	return conflicts
+}
+
+// handleEvents processes endpoint state changes. Events are applied to
+// the known state of the endpoint, which may be more recent than the
+// previous state in the event
+func (cd *IPConflictDetector) handleEvents(rsps chan core.WatchState) {
+	for rsp := range rsps {
+		var prevEp, currEp *mastercfg.CfgEndpointState
+		if rsp.Prev != nil {
+			prevEp, _ = rsp.Prev.(*mastercfg.CfgEndpointState)
+		}
+		if rsp.Curr != nil {
+			currEp, _ = rsp.Curr.(*mastercfg.CfgEndpointState)
+		}
+
+		var epID string
+		switch {
+		case currEp != nil:
+			epID = currEp.ID
+		case prevEp != nil:
+			epID = prevEp.ID
+		default:
+			continue
+		}
+
+		cd.mutex.Lock()
+		if cd.stopCh != nil {
+			cd.seq++
+			cd.epSeqs[epID] = cd.seq
+			cd.setEndpoint(cd.epStates[epID], currEp)
+		}
+		cd.mutex.Unlock()
+	}
+}
+
+// updateEndpoint moves an endpoint from its previous addresses to its
+// current ones, and rechecks the addresses. Either may be nil
+func (cd *IPConflictDetector) updateEndpoint(prevEp, currEp *mastercfg.CfgEndpointState) {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+
+	cd.setEndpoint(prevEp, currEp)
+}
+
+// setEndpoint moves an endpoint from its previous addresses to its current
+// ones and rechecks the addresses. Caller must hold the mutex
+func (cd *IPConflictDetector) setEndpoint(prevEp, currEp *mastercfg.CfgEndpointState) {
+	changed := make(map[string]bool)
+	if prevEp != nil {
+		for _, ipAddr := range endpointAddrs(prevEp) {
+			delete(cd.endpoints[ipAddr], prevEp.ID)
+			if len(cd.endpoints[ipAddr]) == 0 {
+				delete(cd.endpoints, ipAddr)
+			}
+			changed[ipAddr] = true
+		}
+		delete(cd.epStates, prevEp.ID)
+	}
+	if currEp != nil {
+		ep := IPConflictEp{
+			EndpointID:    currEp.ID,
+			NetID:         currEp.NetID,
+			HomingHost:    currEp.HomingHost,
+			ContainerName: currEp.ContainerName,
+		}
+		if netParts := strings.Split(currEp.NetID, "."); len(netParts) > 1 {
+			ep.Tenant = netParts[1]
+		}
+
+		for _, ipAddr := range endpointAddrs(currEp) {
+			if cd.endpoints[ipAddr] == nil {
+				cd.endpoints[ipAddr] = make(map[string]IPConflictEp)
+			}
+			cd.endpoints[ipAddr][currEp.ID] = ep
+			changed[ipAddr] = true
+		}
+		cd.epStates[currEp.ID] = currEp
+	}
+
+	for ipAddr := range changed {
+		cd.checkAddr(ipAddr)
+	}
+}
+
+// checkAddr raises or clears the conflict of an address. Caller must
+// hold the mutex
+func (cd *IPConflictDetector) checkAddr(ipAddr string) {
+	oldConflict, found := cd.conflicts[ipAddr]
+
+	eps := cd.endpoints[ipAddr]
+	if len(eps) < 2 {
+		if found {
+			delete(cd.conflicts, ipAddr)
+			log.Infof("IP conflict on %s cleared", ipAddr)
+			cd.notify(IPConflictEvent{EventType: IPConflictCleared, Conflict: oldConflict})
+		}
+		return
+	}
+
+	conflict := IPConflict{IPAddress: ipAddr, Type: IPConflictEndpoint, Since: time.Now()}
+	if found {
+		conflict.Since = oldConflict.Since
+	}
+	for _, ep := range eps {
+		if len(conflict.Endpoints) != 0 && ep.Tenant != conflict.Endpoints[0].Tenant {
+			conflict.Type = IPConflictTenant
+		}
+		conflict.Endpoints = append(conflict.Endpoints, ep)
+	}
+	sort.Sort(conflictEpsByID(conflict.Endpoints))
+
+	if found && sameConflictEps(oldConflict.Endpoints, conflict.Endpoints) {
+		return
+	}
+
+	cd.conflicts[ipAddr] = conflict
+	log.Warnf("IP conflict on %s between %d endpoints: %+v", ipAddr, len(conflict.Endpoints), conflict.Endpoints)
+	cd.notify(IPConflictEvent{EventType: IPConflictRaised, Conflict: conflict})
+}
+
+// notify sends an event to the subscribers. Caller must hold the mutex
+func (cd *IPConflictDetector) notify(event IPConflictEvent) {
+	for _, eventCh := range cd.listeners {
+		select {
+		case eventCh <- event:
+		default:
+			log.Warnf("Dropping IP conflict event for %s, subscriber is not keeping up", event.Conflict.IPAddress)
+		}
+	}
+}
+
+// endpointAddrs returns the normalized addresses of an endpoint
+func endpointAddrs(epCfg *mastercfg.CfgEndpointState) []string {
+	var addrs []string
+	for _, addr := range []string{epCfg.IPAddress, epCfg.IPv6Address} {
+		if ip := net.ParseIP(strings.Split(addr, "/")[0]); ip != nil {
+			addrs = append(addrs, ip.String())
+		}
+	}
+
+	return addrs
+}
+
+// sameConflictEps compares endpoint lists sorted by id
+func sameConflictEps(eps, otherEps []IPConflictEp) bool {
+	if len(eps) != len(otherEps) {
+		return false
+	}
+	for i := range eps {
+		if eps[i] != otherEps[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// conflictsByIP sorts conflicts by IP address
+type conflictsByIP []IPConflict
+
+func (c conflictsByIP) Len() int           { return len(c) }
+func (c conflictsByIP) Less(i, j int) bool { return c[i].IPAddress < c[j].IPAddress }
+func (c conflictsByIP) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// conflictEpsByID sorts conflict endpoints by endpoint id
+type conflictEpsByID []IPConflictEp
+
+func (c conflictEpsByID) Len() int           { return len(c) }
+func (c conflictEpsByID) Less(i, j int) bool { return c[i].EndpointID < c[j].EndpointID }
+func (c conflictEpsByID) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
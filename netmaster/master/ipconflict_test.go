@@ -0,0 +1,116 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"testing"
+
+	"github.com/contiv/netplugin/netmaster/mastercfg"
+)
+
+func testConflictEp(id, netID, ipAddr string) *mastercfg.CfgEndpointState {
+	epCfg := &mastercfg.CfgEndpointState{NetID: netID, IPAddress: ipAddr}
+	epCfg.ID = id
+	return epCfg
+}
+
+func TestIPConflictDetector(t *testing.T) {
+	cd := NewIPConflictDetector(nil)
+	eventCh := make(chan IPConflictEvent, 10)
+	cd.Subscribe(eventCh)
+
+	ep1 := testConflictEp("ep1", "net1.tenant1", "10.1.1.1")
+	ep2 := testConflictEp("ep2", "net1.tenant1", "10.1.1.1")
+	ep3 := testConflictEp("ep3", "net2.tenant2", "10.1.1.1")
+
+	cd.updateEndpoint(nil, ep1)
+	if len(cd.Conflicts()) != 0 {
+		t.Fatalf("Unexpected conflicts: %+v", cd.Conflicts())
+	}
+
+	cd.updateEndpoint(nil, ep2)
+	conflicts := cd.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Type != IPConflictEndpoint || len(conflicts[0].Endpoints) != 2 {
+		t.Fatalf("Expected endpoint conflict on 10.1.1.1, got: %+v", conflicts)
+	}
+	if event := <-eventCh; event.EventType != IPConflictRaised {
+		t.Fatalf("Expected conflict raised event, got: %+v", event)
+	}
+
+	cd.updateEndpoint(nil, ep3)
+	conflicts = cd.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Type != IPConflictTenant || len(conflicts[0].Endpoints) != 3 {
+		t.Fatalf("Expected tenant conflict on 10.1.1.1, got: %+v", conflicts)
+	}
+	<-eventCh
+
+	cd.updateEndpoint(ep3, nil)
+	cd.updateEndpoint(ep2, testConflictEp("ep2", "net1.tenant1", "10.1.1.2"))
+	if len(cd.Conflicts()) != 0 {
+		t.Fatalf("Conflicts were not cleared: %+v", cd.Conflicts())
+	}
+	<-eventCh
+	if event := <-eventCh; event.EventType != IPConflictCleared {
+		t.Fatalf("Expected conflict cleared event, got: %+v", event)
+	}
+}
+
+func TestIPConflictResync(t *testing.T) {
+	initFakeStateDriver(t)
+	defer deinitFakeStateDriver()
+
+	writeEp := func(epCfg *mastercfg.CfgEndpointState) {
+		epCfg.StateDriver = fakeDriver
+		if err := epCfg.Write(); err != nil {
+			t.Fatalf("Error writing endpoint %s. Err: %v", epCfg.ID, err)
+		}
+	}
+
+	cd := NewIPConflictDetector(fakeDriver)
+	writeEp(testConflictEp("ep1", "net1.tenant1", "10.1.1.1"))
+	writeEp(testConflictEp("ep2", "net1.tenant1", "10.1.1.1"))
+	if err := cd.Start(); err != nil {
+		t.Fatalf("Error starting IP conflict detector. Err: %v", err)
+	}
+	defer cd.Stop()
+
+	if conflicts := cd.Conflicts(); len(conflicts) != 1 {
+		t.Fatalf("Expected conflict on 10.1.1.1, got: %+v", conflicts)
+	}
+
+	// updates the watch missed are found by the next read
+	ep2 := testConflictEp("ep2", "net1.tenant1", "10.1.1.2")
+	writeEp(ep2)
+	if err := cd.resync(); err != nil {
+		t.Fatalf("Error reading endpoints. Err: %v", err)
+	}
+	if conflicts := cd.Conflicts(); len(conflicts) != 0 {
+		t.Fatalf("Conflict was not cleared by resync: %+v", conflicts)
+	}
+	writeEp(testConflictEp("ep2", "net1.tenant1", "10.1.1.1"))
+	if err := cd.resync(); err != nil {
+		t.Fatalf("Error reading endpoints. Err: %v", err)
+	}
+	if conflicts := cd.Conflicts(); len(conflicts) != 1 {
+		t.Fatalf("Expected conflict on 10.1.1.1 after resync, got: %+v", conflicts)
+	}
+
+	// stopping forgets the endpoints
+	cd.Stop()
+	if conflicts := cd.Conflicts(); len(conflicts) != 0 {
+		t.Fatalf("Conflicts were not cleared on stop: %+v", conflicts)
+	}
+}
@@ -24,7 +24,8 @@ import (
 )
 
 const (
-	// StateBasePath is the base path for all state operations.
+	// StateBasePath is the base path for all state operations. The state
+	// drivers move it under the cluster root, see objdb.SetKeyRoot.
 	StateBasePath = "/contiv.io/"
 	// StateConfigPath is the path to the root of the configuration state
 	StateConfigPath = StateBasePath + "state/"
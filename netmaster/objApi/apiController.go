@@ -34,6 +34,7 @@ import (
 
 	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
 
 	log "github.com/Sirupsen/logrus"
@@ -55,14 +56,20 @@ type BgpInspect struct {
 
 var apiCtrler *APIController
 
-// NewAPIController creates a new controller
-func NewAPIController(router *mux.Router, objdbClient objdb.API, storeURL string) *APIController {
+// NewAPIController creates a new controller. Changes to the model are
+// audited if auditConfig is not nil
+func NewAPIController(router *mux.Router, objdbClient objdb.API, storeURL string, auditConfig *objdb.AuditConfig) *APIController {
 	ctrler := new(APIController)
 	ctrler.router = router
 	ctrler.objdbClient = objdbClient
 
 	// init modeldb
 	modeldb.Init(storeURL)
+	if auditConfig != nil {
+		if err := modeldb.EnableAudit(*auditConfig); err != nil {
+			log.Errorf("Error enabling audit of model changes. Err: %v", err)
+		}
+	}
 
 	// initialize the model objects
 	contivModel.Init()
@@ -1856,7 +1863,7 @@ func (ac *APIController) BgpGetOper(bgp *contivModel.BgpInspect) error {
 		}
 	}
 
-	url := "http://" + host + ":9090/inspect/bgp"
+	url := "http://" + net.JoinHostPort(host, "9090") + "/inspect/bgp"
 	r, err := http.Get(url)
 	if err != nil {
 		return err
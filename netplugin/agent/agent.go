@@ -18,6 +18,7 @@ package agent
 import (
 	"net"
 	"net/http"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/contiv/netplugin/mgmtfn/dockplugin"
@@ -27,6 +28,7 @@ import (
 	"github.com/contiv/netplugin/netplugin/cluster"
 	"github.com/contiv/netplugin/netplugin/plugin"
 	"github.com/contiv/netplugin/netplugin/svcplugin"
+	"github.com/contiv/objdb"
 	"github.com/gorilla/mux"
 	"github.com/samalba/dockerclient"
 
@@ -35,6 +37,9 @@ import (
 	_ "github.com/contiv/netplugin/netplugin/svcplugin/skydns2extension"
 )
 
+// How long to wait for the state store to be usable
+const storeReadyTimeout = 5 * time.Minute
+
 // Agent holds the netplugin agent state
 type Agent struct {
 	netPlugin    *plugin.NetPlugin      // driver plugin
@@ -179,8 +184,15 @@ func (ag *Agent) ProcessCurrentState() error {
 func (ag *Agent) PostInit() error {
 	opts := ag.pluginConfig.Instance
 
+	// Wait for the store to be usable before serving requests off it
+	_, err := objdb.WaitForReady(cluster.ObjdbClient, nil, storeReadyTimeout)
+	if err != nil {
+		log.Errorf("State store is not usable. Err: %v", err)
+		return err
+	}
+
 	// Initialize clustering
-	err := cluster.RunLoop(ag.netPlugin, opts.CtrlIP, opts.VtepIP, opts.HostLabel)
+	err = cluster.RunLoop(ag.netPlugin, opts.CtrlIP, opts.VtepIP, opts.HostLabel)
 	if err != nil {
 		log.Errorf("Error starting cluster run loop")
 	}
@@ -18,10 +18,11 @@ package cluster
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/contiv/netplugin/core"
@@ -41,14 +42,21 @@ const (
 	vxlanUDPPort      = 4789
 )
 
+// Features advertised in netplugin's registration, netmaster disables the
+// ones some live agent does not support
+var netpluginCapabilities = []string{"vlan", "vxlan"}
+
 // ObjdbClient client
 var ObjdbClient objdb.API
 
+// Shuts down ObjdbClient once
+var deinitOnce sync.Once
+
 // MasterDB is Database of Master nodes
 var MasterDB = make(map[string]*objdb.ServiceInfo)
 
 func masterKey(srvInfo objdb.ServiceInfo) string {
-	return srvInfo.HostAddr + ":" + fmt.Sprintf("%d", srvInfo.Port)
+	return objdb.InstanceAddr(srvInfo)
 }
 
 // Add a master node
@@ -126,14 +134,18 @@ func HTTPPost(url string, req interface{}, resp interface{}) error {
 
 // getMasterLockHolder returns the IP of current master lock hoder
 func getMasterLockHolder() (string, error) {
-	// Create the lock
-	leaderLock, err := ObjdbClient.NewLock("netmaster/leader", "", 0)
+	// netmaster replicas elect their leader
+	election, err := objdb.NewLeaderElector(ObjdbClient, "netmaster", 0)
 	if err != nil {
-		log.Fatalf("Could not create leader lock. Err: %v", err)
+		log.Fatalf("Could not create netmaster election. Err: %v", err)
 	}
 
-	// get current holder of leader lock
-	masterNode := leaderLock.GetHolder()
+	// get current leader
+	masterNode, err := election.Leader()
+	if err != nil {
+		log.Errorf("Error getting leader node. Err: %v", err)
+		return "", err
+	}
 	if masterNode == "" {
 		log.Errorf("No leader node found")
 		return "", errors.New("No leader node")
@@ -169,9 +181,14 @@ func MasterPostReq(path string, req interface{}, resp interface{}) error {
 		return err
 	}
 
-	// Walk all netmasters and see if any of them respond
+	// Walk all netmasters, the one on this host first, and see if any of
+	// them respond
+	var masters []objdb.ServiceInfo
 	for _, master := range MasterDB {
-		url := "http://" + master.HostAddr + ":9999" + path
+		masters = append(masters, *master)
+	}
+	for _, master := range objdb.PreferColocated(masters, objdb.LocalNode()) {
+		url := "http://" + net.JoinHostPort(master.HostAddr, "9999") + path
 
 		log.Infof("Making REST request to url: %s", url)
 
@@ -192,30 +209,32 @@ func MasterPostReq(path string, req interface{}, resp interface{}) error {
 func registerService(objClient objdb.API, ctrlIP, vtepIP, hostname string) error {
 	// netplugin service info
 	srvInfo := objdb.ServiceInfo{
-		ServiceName: "netplugin",
-		TTL:         10,
-		HostAddr:    ctrlIP,
-		Port:        netpluginRPCPort1,
-		Hostname:    hostname,
+		ServiceName:  "netplugin",
+		TTL:          10,
+		HostAddr:     ctrlIP,
+		Port:         netpluginRPCPort1,
+		Hostname:     hostname,
+		Capabilities: netpluginCapabilities,
 	}
 
-	// Register the node with service registry
-	err := objClient.RegisterService(srvInfo)
+	// Register the node with service registry, again if it is lost
+	err := objdb.KeepRegistered(objClient, srvInfo)
 	if err != nil {
 		log.Fatalf("Error registering service. Err: %v", err)
 		return err
 	}
 
 	srvInfo = objdb.ServiceInfo{
-		ServiceName: "netplugin",
-		TTL:         10,
-		HostAddr:    ctrlIP,
-		Port:        netpluginRPCPort2,
-		Hostname:    hostname,
+		ServiceName:  "netplugin",
+		TTL:          10,
+		HostAddr:     ctrlIP,
+		Port:         netpluginRPCPort2,
+		Hostname:     hostname,
+		Capabilities: netpluginCapabilities,
 	}
 
-	// Register the node with service registry
-	err = objClient.RegisterService(srvInfo)
+	// Register the node with service registry, again if it is lost
+	err = objdb.KeepRegistered(objClient, srvInfo)
 	if err != nil {
 		log.Fatalf("Error registering service. Err: %v", err)
 		return err
@@ -229,8 +248,8 @@ func registerService(objClient objdb.API, ctrlIP, vtepIP, hostname string) error
 		Port:        vxlanUDPPort,
 	}
 
-	// Register the node with service registry
-	err = objClient.RegisterService(srvInfo)
+	// Register the node with service registry, again if it is lost
+	err = objdb.KeepRegistered(objClient, srvInfo)
 	if err != nil {
 		log.Fatalf("Error registering service. Err: %v", err)
 		return err
@@ -353,8 +372,26 @@ func Init(storeURL string) error {
 	return nil
 }
 
+// Deinit deregisters netplugin services and shuts down the objdb client.
+// ObjdbClient stays set, as event handlers may still be using it, and
+// only the first call does anything, so exit paths may race to call it
+func Deinit() {
+	deinitOnce.Do(func() {
+		if ObjdbClient == nil {
+			return
+		}
+
+		if err := ObjdbClient.Deinit(); err != nil {
+			log.Errorf("Error shutting down objdb client. Err: %v", err)
+		}
+	})
+}
+
 // RunLoop registers netplugin service with cluster store and runs peer discovery
 func RunLoop(netplugin *plugin.NetPlugin, ctrlIP, vtepIP, hostname string) error {
+	// services on this host register with our addresses
+	objdb.SetLocalNode(objdb.NodeInfo{Hostname: hostname, HostAddrs: []string{ctrlIP, vtepIP}})
+
 	// Register ourselves
 	err := registerService(ObjdbClient, ctrlIP, vtepIP, hostname)
 
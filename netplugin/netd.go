@@ -19,8 +19,10 @@ import (
 	"log/syslog"
 	"net/url"
 	"os"
+	"os/signal"
 	"os/user"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/contiv/netplugin/core"
@@ -28,6 +30,7 @@ import (
 	"github.com/contiv/netplugin/netplugin/cluster"
 	"github.com/contiv/netplugin/netplugin/plugin"
 	"github.com/contiv/netplugin/version"
+	"github.com/contiv/objdb"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/Sirupsen/logrus/hooks/syslog"
@@ -48,6 +51,8 @@ type cliOpts struct {
 	vlanIntf   string // Uplink interface for VLAN switching
 	version    bool
 	dbURL      string // state store URL
+	dbRoot     string // root of the keys of this cluster in the state store
+	zone       string // Failure domain of this host
 }
 
 func configureSyslog(syslogParam string) {
@@ -132,6 +137,14 @@ func main() {
 		"cluster-store",
 		"etcd://127.0.0.1:2379",
 		"state store url")
+	flagSet.StringVar(&opts.dbRoot,
+		"cluster-root",
+		objdb.KeyRoot(),
+		"root of the keys of this cluster in the state store, eg. cluster1/contiv.io")
+	flagSet.StringVar(&opts.zone,
+		"zone",
+		"",
+		"failure domain of this host, eg. an availability zone. Use 'auto' to read it from the cloud metadata")
 
 	err = flagSet.Parse(os.Args[1:])
 	if err != nil {
@@ -180,6 +193,15 @@ func main() {
 		opts.vtepIP = opts.ctrlIP
 	}
 
+	// zone is advertised in our service registrations
+	if opts.zone == "auto" {
+		opts.zone, err = objdb.DetectZone()
+		if err != nil {
+			log.Warnf("Could not detect the zone of this host. Err: %v", err)
+		}
+	}
+	objdb.SetLocalZone(opts.zone)
+
 	// parse store URL
 	parts := strings.Split(opts.dbURL, "://")
 	if len(parts) < 2 {
@@ -187,6 +209,11 @@ func main() {
 	}
 	stateStore := parts[0]
 
+	// clusters sharing a store keep their keys apart
+	if err := objdb.SetKeyRoot(opts.dbRoot); err != nil {
+		log.Fatalf("Invalid cluster-root %s. Err: %v", opts.dbRoot, err)
+	}
+
 	// initialize the config
 	pluginConfig := plugin.Config{
 		Drivers: plugin.Drivers{
@@ -210,11 +237,24 @@ func main() {
 	ag.ProcessCurrentState()
 
 	// post initialization processing
-	ag.PostInit()
+	if err := ag.PostInit(); err != nil {
+		log.Fatalf("Error in post initialization. Err: %v", err)
+	}
+
+	// deregister our services right away when we are stopped
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		log.Infof("Netplugin exiting on signal %v", sig)
+		cluster.Deinit()
+		os.Exit(0)
+	}()
 
 	// handle events
 	if err := ag.HandleEvents(); err != nil {
 		log.Infof("Netplugin exiting due to error: %v", err)
+		cluster.Deinit()
 		os.Exit(1)
 	}
 }
@@ -46,6 +46,8 @@ func initStateDriver(clusterStore string) (core.StateDriver, error) {
 	// Make sure we support the statestore type
 	switch stateStore {
 	case utils.EtcdNameStr:
+	case utils.EtcdSRVNameStr:
+	case utils.EtcdTLSNameStr:
 	case utils.ConsulNameStr:
 	default:
 		return nil, core.Errorf("Unsupported state-store %q", stateStore)
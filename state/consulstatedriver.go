@@ -67,7 +67,7 @@ func (d *ConsulStateDriver) Deinit() {
 
 func processKey(inKey string) string {
 	//consul doesn't accepts keys starting with a '/', so trim the leading slash
-	return strings.TrimPrefix(inKey, "/")
+	return strings.TrimPrefix(stateKey(inKey), "/")
 }
 
 // Write state to key with value.
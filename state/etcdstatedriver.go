@@ -17,6 +17,7 @@ package state
 
 import (
 	"errors"
+	"net/http"
 	"reflect"
 	"strings"
 	"time"
@@ -24,6 +25,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/contiv/netplugin/core"
+	"github.com/contiv/objdb"
 	"github.com/coreos/etcd/client"
 
 	log "github.com/Sirupsen/logrus"
@@ -53,13 +55,45 @@ type EtcdStateDriver struct {
 func (d *EtcdStateDriver) Init(instInfo *core.InstanceInfo) error {
 	var err error
 
-	if instInfo == nil || !strings.Contains(instInfo.DbURL, "etcd://") {
+	if instInfo == nil {
+		return errors.New("Invalid etcd config")
+	}
+
+	var endpoints []string
+	var transport client.CancelableTransport
+	switch {
+	case strings.HasPrefix(instInfo.DbURL, "etcd+srv://"):
+		// discover the endpoints from SRV records of a DNS domain
+		endpoints, err = objdb.DiscoverEtcdEndpoints(strings.TrimPrefix(instInfo.DbURL, "etcd+srv://"))
+		if err != nil {
+			return err
+		}
+	case strings.HasPrefix(instInfo.DbURL, "etcd+https://"):
+		// certificates are passed as URL options
+		config, err := objdb.ParseTLSURL(strings.TrimPrefix(instInfo.DbURL, "etcd+https://"))
+		if err != nil {
+			return err
+		}
+		tlsConfig, err := objdb.EtcdTLSConfig(config)
+		if err != nil {
+			log.Errorf("Invalid etcd TLS config. Err: %v", err)
+			return err
+		}
+		endpoints = config.Endpoints
+		transport = &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSHandshakeTimeout: 10 * time.Second,
+			TLSClientConfig:     tlsConfig,
+		}
+	case strings.Contains(instInfo.DbURL, "etcd://"):
+		endpoints = []string{strings.Replace(instInfo.DbURL, "etcd://", "http://", 1)}
+	default:
 		return errors.New("Invalid etcd config")
 	}
 
-	etcdURL := strings.Replace(instInfo.DbURL, "etcd://", "http://", 1)
 	etcdConfig := client.Config{
-		Endpoints: []string{etcdURL},
+		Endpoints: endpoints,
+		Transport: transport,
 	}
 
 	d.Client, err = client.New(etcdConfig)
@@ -78,6 +112,7 @@ func (d *EtcdStateDriver) Deinit() {}
 
 // Write state to key with value.
 func (d *EtcdStateDriver) Write(key string, value []byte) error {
+	key = stateKey(key)
 	ctx, cancel := context.WithTimeout(context.Background(), ctxTimeout)
 	defer cancel()
 
@@ -102,6 +137,7 @@ func (d *EtcdStateDriver) Write(key string, value []byte) error {
 
 // Read state from key.
 func (d *EtcdStateDriver) Read(key string) ([]byte, error) {
+	key = stateKey(key)
 	ctx, cancel := context.WithTimeout(context.Background(), ctxTimeout)
 	defer cancel()
 
@@ -128,6 +164,7 @@ func (d *EtcdStateDriver) Read(key string) ([]byte, error) {
 
 // ReadAll state from baseKey.
 func (d *EtcdStateDriver) ReadAll(baseKey string) ([][]byte, error) {
+	baseKey = stateKey(baseKey)
 	ctx, cancel := context.WithTimeout(context.Background(), ctxTimeout)
 	defer cancel()
 
@@ -182,6 +219,7 @@ func (d *EtcdStateDriver) channelEtcdEvents(watcher client.Watcher, rsps chan [2
 
 // WatchAll state transitions from baseKey
 func (d *EtcdStateDriver) WatchAll(baseKey string, rsps chan [2][]byte) error {
+	baseKey = stateKey(baseKey)
 	watcher := d.KeysAPI.Watcher(baseKey, &client.WatcherOptions{Recursive: true})
 	if watcher == nil {
 		log.Errorf("etcd watch failed.")
@@ -195,6 +233,7 @@ func (d *EtcdStateDriver) WatchAll(baseKey string, rsps chan [2][]byte) error {
 
 // ClearState removes key from etcd
 func (d *EtcdStateDriver) ClearState(key string) error {
+	key = stateKey(key)
 	ctx, cancel := context.WithTimeout(context.Background(), ctxTimeout)
 	defer cancel()
 
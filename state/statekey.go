@@ -0,0 +1,38 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"strings"
+
+	"github.com/contiv/objdb"
+)
+
+// Base path of the state keys of netmaster and netplugin, see
+// mastercfg.StateBasePath
+const defaultStateBase = "/contiv.io/"
+
+// stateKey moves a state key under the key root of the cluster, so that
+// clusters sharing a store keep their state apart like their objdb keys.
+// See objdb.SetKeyRoot
+func stateKey(key string) string {
+	root := "/" + objdb.KeyRoot() + "/"
+	if root == defaultStateBase || !strings.HasPrefix(key, defaultStateBase) {
+		return key
+	}
+
+	return root + strings.TrimPrefix(key, defaultStateBase)
+}
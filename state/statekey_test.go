@@ -0,0 +1,48 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"testing"
+
+	"github.com/contiv/objdb"
+)
+
+func TestStateKey(t *testing.T) {
+	defaultRoot := objdb.KeyRoot()
+	defer objdb.SetKeyRoot(defaultRoot)
+
+	testCases := []struct {
+		root string
+		key  string
+		exp  string
+	}{
+		{root: "contiv.io", key: "/contiv.io/state/nets/net1", exp: "/contiv.io/state/nets/net1"},
+		{root: "cluster1/contiv.io", key: "/contiv.io/state/nets/net1", exp: "/cluster1/contiv.io/state/nets/net1"},
+		{root: "cluster1/contiv.io", key: "/contiv.io/oper/", exp: "/cluster1/contiv.io/oper/"},
+		{root: "cluster1/contiv.io", key: "/other/key", exp: "/other/key"},
+		{root: "cluster1/contiv.io", key: "/cluster1/contiv.io/oper/", exp: "/cluster1/contiv.io/oper/"},
+	}
+
+	for _, tc := range testCases {
+		if err := objdb.SetKeyRoot(tc.root); err != nil {
+			t.Fatalf("Error setting key root %s. Err: %v", tc.root, err)
+		}
+		if key := stateKey(tc.key); key != tc.exp {
+			t.Fatalf("Key %s is %s under root %s, expected %s", tc.key, key, tc.root, tc.exp)
+		}
+	}
+}
@@ -696,7 +696,7 @@ func (s systemtestSuite) testServiceTriggerNetmasterSwitchover(c *C, encap strin
 				}
 			}
 
-			leaderIP, err := s.clusterStoreGet("/contiv.io/lock/netmaster/leader")
+			leaderIP, err := s.clusterStoreGet("/contiv.io/lock/election/netmaster")
 			c.Assert(err, IsNil)
 
 			for _, node := range s.nodes {
@@ -714,7 +714,7 @@ func (s systemtestSuite) testServiceTriggerNetmasterSwitchover(c *C, encap strin
 
 			for x := 0; x < 15; x++ {
 				logrus.Info("Waiting 5s for leader to change...")
-				newLeaderIP, err := s.clusterStoreGet("/contiv.io/lock/netmaster/leader")
+				newLeaderIP, err := s.clusterStoreGet("/contiv.io/lock/election/netmaster")
 				c.Assert(err, IsNil)
 
 				for _, node := range s.nodes {
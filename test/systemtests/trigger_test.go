@@ -38,7 +38,7 @@ func (s *systemtestSuite) TestTriggerNetmasterSwitchover(c *C) {
 
 		var leader, oldLeader *node
 
-		leaderIP, err := s.clusterStoreGet("/contiv.io/lock/netmaster/leader")
+		leaderIP, err := s.clusterStoreGet("/contiv.io/lock/election/netmaster")
 		c.Assert(err, IsNil)
 
 		for _, node := range s.nodes {
@@ -55,7 +55,7 @@ func (s *systemtestSuite) TestTriggerNetmasterSwitchover(c *C) {
 
 		for x := 0; x < 15; x++ {
 			logrus.Info("Waiting 5s for leader to change...")
-			newLeaderIP, err := s.clusterStoreGet("/contiv.io/lock/netmaster/leader")
+			newLeaderIP, err := s.clusterStoreGet("/contiv.io/lock/election/netmaster")
 			c.Assert(err, IsNil)
 
 			for _, node := range s.nodes {
@@ -33,6 +33,14 @@ var stateDriverRegistry = map[string]driverConfigTypes{
 		DriverType: reflect.TypeOf(state.EtcdStateDriver{}),
 		ConfigType: reflect.TypeOf(state.EtcdStateDriverConfig{}),
 	},
+	EtcdSRVNameStr: driverConfigTypes{
+		DriverType: reflect.TypeOf(state.EtcdStateDriver{}),
+		ConfigType: reflect.TypeOf(state.EtcdStateDriverConfig{}),
+	},
+	EtcdTLSNameStr: driverConfigTypes{
+		DriverType: reflect.TypeOf(state.EtcdStateDriver{}),
+		ConfigType: reflect.TypeOf(state.EtcdStateDriverConfig{}),
+	},
 	ConsulNameStr: driverConfigTypes{
 		DriverType: reflect.TypeOf(state.ConsulStateDriver{}),
 		ConfigType: reflect.TypeOf(state.ConsulStateDriverConfig{}),
@@ -47,6 +55,12 @@ var stateDriverRegistry = map[string]driverConfigTypes{
 const (
 	// EtcdNameStr is a string constant for etcd state-store
 	EtcdNameStr = "etcd"
+	// EtcdSRVNameStr is a string constant for etcd state-store whose
+	// endpoints are discovered thru DNS SRV records
+	EtcdSRVNameStr = "etcd+srv"
+	// EtcdTLSNameStr is a string constant for etcd state-store reached
+	// over TLS
+	EtcdTLSNameStr = "etcd+https"
 	// ConsulNameStr is a string constant for consul state-store
 	ConsulNameStr = "consul"
 	// OvsNameStr is a string constant for ovs driver